@@ -0,0 +1,161 @@
+// Package chat persists multi-turn conversation history for gpt-cli-tool so
+// a follow-up refinement (e.g. "make it recursive") can be sent as a
+// continuation of a prior translation instead of starting a fresh
+// conversation with the model.
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Message is one turn of a persisted conversation.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Session is a named, persisted conversation.
+type Session struct {
+	ID       string    `json:"id"`
+	Messages []Message `json:"messages"`
+}
+
+// Info summarizes a persisted session for -list-sessions.
+type Info struct {
+	ID       string
+	Turns    int
+	Modified time.Time
+}
+
+func sessionsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".gpt-cli-tool", "sessions"), nil
+}
+
+// errInvalidSessionID is returned when a session id could escape the
+// sessions directory, e.g. via ".." or a path separator.
+var errInvalidSessionID = errors.New("invalid session id: must not contain path separators or \"..\"")
+
+func sessionPath(id string) (string, error) {
+	if id == "" || id != filepath.Base(id) || strings.Contains(id, "..") {
+		return "", errInvalidSessionID
+	}
+
+	dir, err := sessionsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, id+".json"), nil
+}
+
+// Load returns the session stored under id, or a new empty session if none
+// exists yet. The session is only persisted once Save is called.
+func Load(id string) (*Session, error) {
+	path, err := sessionPath(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Session{ID: id}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Append adds a turn to the session's history.
+func (s *Session) Append(role, content string) {
+	s.Messages = append(s.Messages, Message{Role: role, Content: content})
+}
+
+// Save persists the session to ~/.gpt-cli-tool/sessions/<id>.json.
+func (s *Session) Save() error {
+	dir, err := sessionsDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	path, err := sessionPath(s.ID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// List returns metadata for every persisted session, most recently modified
+// first.
+func List() ([]Info, error) {
+	dir, err := sessionsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []Info
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		id := strings.TrimSuffix(entry.Name(), ".json")
+		fileInfo, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		s, err := Load(id)
+		if err != nil {
+			continue
+		}
+
+		infos = append(infos, Info{ID: id, Turns: len(s.Messages), Modified: fileInfo.ModTime()})
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Modified.After(infos[j].Modified) })
+	return infos, nil
+}
+
+// MostRecent returns the ID of the most recently modified session, for the
+// -resume flag.
+func MostRecent() (string, error) {
+	infos, err := List()
+	if err != nil {
+		return "", err
+	}
+	if len(infos) == 0 {
+		return "", errors.New("no sessions found")
+	}
+	return infos[0].ID, nil
+}
@@ -0,0 +1,74 @@
+// Package config loads gpt-cli-tool's optional ~/.config/gpt-cli-tool/config.yaml,
+// which can define named model profiles and per-shell system-prompt
+// templates so power users can tune translation quality without a rebuild.
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profile is one named, selectable set of translation settings.
+type Profile struct {
+	Name         string  `yaml:"name"`
+	Provider     string  `yaml:"provider,omitempty"`
+	Model        string  `yaml:"model,omitempty"`
+	Temperature  float32 `yaml:"temperature,omitempty"`
+	SystemPrompt string  `yaml:"system_prompt,omitempty"`
+}
+
+// Config is the parsed contents of config.yaml.
+type Config struct {
+	Profiles []Profile `yaml:"profiles"`
+	// ShellPrompts maps a detected parent process executable name, exactly
+	// as reported (bash, zsh, pwsh, fish, nu, or on Windows cmd.exe,
+	// pwsh.exe, powershell.exe, ...), to a system-prompt template, for
+	// shells whose translation quality benefits from shell-specific hints.
+	ShellPrompts map[string]string `yaml:"shell_prompts"`
+}
+
+// DefaultPath returns ~/.config/gpt-cli-tool/config.yaml.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "gpt-cli-tool", "config.yaml"), nil
+}
+
+// Load parses the config file at path. A missing file is not an error; it
+// yields an empty Config so callers can fall back to built-in defaults.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Profile looks up a named profile.
+func (c *Config) Profile(name string) (Profile, bool) {
+	for _, p := range c.Profiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// ShellPrompt looks up the system-prompt template for a detected shell.
+func (c *Config) ShellPrompt(shell string) (string, bool) {
+	prompt, ok := c.ShellPrompts[shell]
+	return prompt, ok
+}
@@ -12,12 +12,20 @@ Usage: gpt-cli-tool [options] <pseudo command>
 
 	This tool takes a 'pseudo command' as input and attempts to translate it
 	into an executable command for various operating system platforms and command shells,
-	leveraging the OpenAI API for command transformation and execution.
+	leveraging an LLM provider for command transformation and execution.
 
 Options:
 
 	-v              Enable verbose output.
-	-m <model>      Specify the OpenAI model to use for command translation (default: gpt-3.5-turbo).
+	-m <model>      Specify the model to use for command translation (default: gpt-3.5-turbo; other providers than openai then use their own default model).
+	-p <provider>   Specify the LLM provider to use: openai, anthropic, mistral or localai (default: openai, or $LLM_PROVIDER).
+	-stream         Stream the translated command token by token (openai provider only).
+	-c <session>    Persist and reuse conversation history under this session name.
+	-resume         Resume the most recently used chat session.
+	-list-sessions  List saved chat sessions and exit.
+	-profile <name> Use a named profile from ~/.config/gpt-cli-tool/config.yaml.
+	-temperature <n> Sampling temperature, overrides the active profile's temperature. 0 is not sent, so the provider's default applies.
+	-system-prompt <text> System prompt sent to the model, overrides the active profile/shell template.
 	-V              Display the version of gpt-cli-tool.
 
 Example:
@@ -29,13 +37,10 @@ The above example will output the translated command in verbose mode using the g
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"bufio"
 	"flag"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -45,23 +50,51 @@ import (
 
 	"github.com/fatih/color"
 	ps "github.com/mitchellh/go-ps"
+
+	"github.com/hgrimm/gpt-cli-tool/chat"
+	"github.com/hgrimm/gpt-cli-tool/config"
 )
 
 var (
-	verbose        bool
-	displayVersion bool
-	model          string
+	verbose          bool
+	displayVersion   bool
+	model            string
+	provider         string
+	stream           bool
+	sessionID        string
+	listSessions     bool
+	resume           bool
+	profileName      string
+	temperatureFlag  float64
+	systemPromptFlag string
 )
 
 const (
-	version    = "0.1"
-	apiKeyInfo = "Goto https://platform.openai.com/account/api-keys to get your API key. Set the API key on CLI by 'export OPENAI_API_KEY=key' on Linux and MacOS or $Env:OPENAI_API_KEY = 'key' on Windows PowerShell\n\n"
+	version = "0.1"
+	// defaultModelFlag is the -m default; providers other than OpenAI fall
+	// back to their own default model when this value wasn't overridden.
+	defaultModelFlag = "gpt-3.5-turbo"
 )
 
+func defaultProvider() string {
+	if p := os.Getenv("LLM_PROVIDER"); p != "" {
+		return p
+	}
+	return providerOpenAI
+}
+
 func init() {
 	flag.BoolVar(&verbose, "v", false, "verbose")
-	flag.StringVar(&model, "m", "gpt-3.5-turbo", "OpenAI model (gpt-3.5-turbo, gpt-4, ...)\nFor further information, refer to https://platform.openai.com/docs/models/overview")
+	flag.StringVar(&model, "m", defaultModelFlag, "model to use for command translation\nFor OpenAI models, refer to https://platform.openai.com/docs/models/overview")
+	flag.StringVar(&provider, "p", defaultProvider(), "LLM provider to use ("+providerOpenAI+", "+providerAnthropic+", "+providerMistral+", "+providerLocalAI+")\ndefaults to $LLM_PROVIDER if set")
 	flag.BoolVar(&displayVersion, "V", false, "display version")
+	flag.BoolVar(&stream, "stream", false, "stream the translated command token by token as it arrives (currently only supported by the openai provider)")
+	flag.StringVar(&sessionID, "c", "", "persist and reuse conversation history under this session name, enabling 'r <refinement>' at the confirmation prompt")
+	flag.BoolVar(&listSessions, "list-sessions", false, "list saved chat sessions and exit")
+	flag.BoolVar(&resume, "resume", false, "resume the most recently used chat session")
+	flag.StringVar(&profileName, "profile", "", "use a named profile from ~/.config/gpt-cli-tool/config.yaml")
+	flag.Float64Var(&temperatureFlag, "temperature", 0, "sampling temperature, overrides the active profile's temperature\n0 is not sent, so the provider's default temperature applies")
+	flag.StringVar(&systemPromptFlag, "system-prompt", "", "system prompt sent to the model, overrides the active profile/shell template")
 }
 
 func debugPrintf(format string, args ...interface{}) {
@@ -70,133 +103,104 @@ func debugPrintf(format string, args ...interface{}) {
 	}
 }
 
-type ChatCompletionRequest struct {
-	Model            string                  `json:"model"`
-	Messages         []ChatCompletionMessage `json:"messages"`
-	MaxTokens        int                     `json:"max_tokens,omitempty"`
-	Temperature      float32                 `json:"temperature,omitempty"`
-	TopP             float32                 `json:"top_p,omitempty"`
-	N                int                     `json:"n,omitempty"`
-	Stream           bool                    `json:"stream,omitempty"`
-	Stop             []string                `json:"stop,omitempty"`
-	PresencePenalty  float32                 `json:"presence_penalty,omitempty"`
-	FrequencyPenalty float32                 `json:"frequency_penalty,omitempty"`
-	// LogitBias is must be a token id string (specified by their token ID in the tokenizer), not a word string.
-	// incorrect: `"logit_bias":{"You": 6}`, correct: `"logit_bias":{"1639": 6}`
-	// refs: https://platform.openai.com/docs/api-reference/chat/create#chat/create-logit_bias
-	LogitBias    map[string]int       `json:"logit_bias,omitempty"`
-	User         string               `json:"user,omitempty"`
-	Functions    []FunctionDefinition `json:"functions,omitempty"`
-	FunctionCall any                  `json:"function_call,omitempty"`
-}
-
-type ChatCompletionMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+// confirmAction is the user's response at the confirmation prompt.
+type confirmAction int
 
-	// This property isn't in the official documentation, but it's in
-	// the documentation for the official library for python:
-	// - https://github.com/openai/openai-python/blob/main/chatml.md
-	// - https://github.com/openai/openai-cookbook/blob/main/examples/How_to_count_tokens_with_tiktoken.ipynb
-	Name string `json:"name,omitempty"`
-
-	FunctionCall *FunctionCall `json:"function_call,omitempty"`
-}
-
-type FunctionCall struct {
-	Name string `json:"name,omitempty"`
-	// call function with arguments in JSON format
-	Arguments string `json:"arguments,omitempty"`
-}
+const (
+	actionAbort confirmAction = iota
+	actionRun
+	actionRefine
+)
 
-type FunctionDefinition struct {
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"`
-	// Parameters is an object describing the function.
-	// You can pass json.RawMessage to describe the schema,
-	// or you can pass in a struct which serializes to the proper JSON schema.
-	// The jsonschema package is provided for convenience, but you should
-	// consider another specialized library if you require more complex schemas.
-	Parameters any `json:"parameters"`
-}
+// promptAction asks the user whether command should be run, escalating the
+// confirmation required based on level: a plain y/n for "safe", an explicit
+// warning banner for "warn", and a full retype of the command for
+// "dangerous". notes (from assessSafety) are printed first. When allowRefine
+// is set (an active chat session) and level is not "dangerous", typing
+// "r <refinement>" requests a follow-up translation instead of running or
+// aborting; at the "dangerous" retype prompt it aborts like any mismatch.
+func promptAction(command, level string, notes []string, allowRefine bool) (confirmAction, string) {
+	reader := bufio.NewReader(os.Stdin)
+
+	for _, note := range notes {
+		fmt.Println(note)
+	}
 
-func makeCommand(pseudoCommand, commandShell string) (string, map[string]interface{}) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	// check if OPENAI_API_KEY is set
-	if apiKey == "" {
-		fmt.Printf("Error: OPENAI_API_KEY is not set. %s", apiKeyInfo)
-		os.Exit(1)
+	if level == "dangerous" {
+		fmt.Printf("DANGER: this command is classified as dangerous. Type it exactly to confirm:\n%s\n> ", command)
+		typed, _ := reader.ReadString('\n')
+		if strings.TrimRight(typed, "\r\n") == command {
+			return actionRun, ""
+		}
+		return actionAbort, ""
 	}
-	debugPrintf("OPENAI_API_KEY: %s", apiKey)
-
-	url := "https://api.openai.com/v1/chat/completions"
-	debugPrintf("API URL: %s", url)
-
-	plattform := runtime.GOOS
-	debugPrintf("plattform: %s", plattform)
-
-	payload := ChatCompletionRequest{
-		Model: model,
-		Messages: []ChatCompletionMessage{
-			{
-				Role: "user",
-				Content: "Convert this pseudo command into a real command that can be run on " +
-					plattform + " and " + commandShell + " command shell. Note that the command might include misspelled, invalid or " +
-					"imagined arguments or even imagined program names. Try your best to convert it " +
-					"into an actual command that would do what the command seems to be intended to do.\n\n" +
-					pseudoCommand + "\n\nRespond only with the command.",
-			},
-		},
-		MaxTokens: 1000,
+
+	if level == "warn" {
+		fmt.Println("WARNING: this command was flagged as potentially risky. Review it carefully before running.")
 	}
 
-	data, err := json.Marshal(payload)
-	debugPrintf("\n%s\n", data)
-	if err != nil {
-		fmt.Println("Error marshaling payload:", err)
-		os.Exit(1)
+	prompt := "Run? (y/n) "
+	if allowRefine {
+		prompt = "Run? (y/n, or 'r <refinement>' to refine) "
 	}
+	fmt.Print(prompt)
+	line, _ := reader.ReadString('\n')
+	line = strings.TrimSpace(line)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
-	if err != nil {
-		fmt.Println("Error creating request:", err)
-		os.Exit(1)
+	if allowRefine && strings.HasPrefix(line, "r ") {
+		return actionRefine, strings.TrimPrefix(line, "r ")
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
+	if line == "y" {
+		return actionRun, ""
+	}
+	return actionAbort, ""
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error:", err)
-		os.Exit(1)
+// historyTurns converts a chat session's persisted messages into the
+// ChatTurn shape providers expect.
+func historyTurns(s *chat.Session) []ChatTurn {
+	turns := make([]ChatTurn, len(s.Messages))
+	for i, m := range s.Messages {
+		turns[i] = ChatTurn{Role: m.Role, Content: m.Content}
 	}
-	defer resp.Body.Close()
+	return turns
+}
 
-	body, err := io.ReadAll(resp.Body)
+// translate runs req against llmProvider, streaming the answer to stdout
+// when allowStream is set and the provider supports it, otherwise blocking
+// until the full response arrives.
+func translate(llmProvider Provider, req TranslateRequest, allowStream bool) (TranslateResponse, error) {
+	if allowStream {
+		if streamingProvider, ok := llmProvider.(StreamingProvider); ok {
+			fmt.Printf("Looks insanely complicated? Don't panic. The answer is ...\n")
+			tokens, resultCh, err := streamingProvider.TranslateStream(req)
+			if err != nil {
+				return TranslateResponse{}, err
+			}
+			cyan := color.New(color.FgCyan)
+			for token := range tokens {
+				cyan.Print(token)
+			}
+			fmt.Println()
+			result := <-resultCh
+			if totalTokens, ok := result.Usage["total_tokens"]; ok {
+				fmt.Printf("number of tokens used (total_tokens): %.1f\n", totalTokens)
+			}
+			return result, nil
+		}
+		fmt.Printf("Warning: -stream is not supported by the %s provider, falling back to a blocking request.\n", provider)
+	}
 
+	result, err := llmProvider.Translate(req)
 	if err != nil {
-		fmt.Println("Error reading response:", err)
-		os.Exit(1)
+		return TranslateResponse{}, err
 	}
-
-	var result map[string]interface{}
-	json.Unmarshal(body, &result)
-	// check if error is returned in result map
-	if _, ok := result["error"]; ok {
-		errorDetails := result["error"].(map[string]interface{})
-		fmt.Printf("Error code %s: %s", errorDetails["code"], errorDetails["message"])
-		os.Exit(1)
+	fmt.Printf("Looks insanely complicated? Don't panic. The answer is ...\n")
+	if totalTokens, ok := result.Usage["total_tokens"]; ok {
+		fmt.Printf("number of tokens used (total_tokens): %.1f\n", totalTokens)
 	}
-	debugPrintf("result:\n%#v\n", result)
-	choices := result["choices"].([]interface{})
-	firstChoice := choices[0].(map[string]interface{})
-	message := firstChoice["message"].(map[string]interface{})
-	content := message["content"].(string)
-	usage := result["usage"].(map[string]interface{})
-	debugPrintf("usage:\n%#v\n", usage)
-
-	return content, usage
+	color.Cyan(result.Command)
+	return result, nil
 }
 
 func main() {
@@ -210,8 +214,9 @@ func main() {
 
 		fmt.Fprintf(os.Stderr, "Convert a pseudo command into a real command that can be run on "+runtime.GOOS+" and "+parentProcessName+" command shell.\n\n")
 		fmt.Fprintf(os.Stderr, "%s-%s by Herwig Grimm <herwig.grimm@gmail.com>\n\n", thisCommand, version)
-		fmt.Fprintf(os.Stderr, "Usage: "+thisCommand+" [-v] [-m <model>] <pseudo command>\n\n")
-		fmt.Fprintf(os.Stderr, "Command requires API key from OpenAI. "+apiKeyInfo)
+		fmt.Fprintf(os.Stderr, "Usage: "+thisCommand+" [-v] [-m <model>] [-p <provider>] <pseudo command>\n\n")
+		fmt.Fprintf(os.Stderr, "Command requires an API key for the selected provider (OPENAI_API_KEY, ANTHROPIC_API_KEY, MISTRAL_API_KEY) "+
+			"or LOCALAI_BASE_URL for a local OpenAI-compatible server.\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 	}
@@ -223,23 +228,99 @@ func main() {
 		os.Exit(0)
 	}
 
+	if listSessions {
+		infos, err := chat.List()
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if len(infos) == 0 {
+			fmt.Println("No saved sessions.")
+		}
+		for _, info := range infos {
+			fmt.Printf("%s\t%d turn(s)\t%s\n", info.ID, info.Turns, info.Modified.Format(time.RFC3339))
+		}
+		os.Exit(0)
+	}
+
+	if resume {
+		id, err := chat.MostRecent()
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		sessionID = id
+	}
+
+	var session *chat.Session
+	if sessionID != "" {
+		var err error
+		session, err = chat.Load(sessionID)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	}
+
 	parentProcessId := os.Getppid()
 	parentProcess, _ := ps.FindProcess(parentProcessId)
 	parentProcessName := parentProcess.Executable()
 	debugPrintf("parent process name %s\n", parentProcessName)
 
-	args := os.Args[1:]
-	pseudo := strings.Join(args, " ")
+	flagsSet := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { flagsSet[f.Name] = true })
 
-	if verbose {
-		// remove -v from pseudo command
-		pseudo = strings.Replace(pseudo, "-v", "", 1)
+	cfgPath, err := config.DefaultPath()
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		fmt.Println("Error loading config:", err)
+		os.Exit(1)
 	}
 
-	// remove flag -m and model
-	pseudo = strings.Replace(pseudo, "-m "+model, "", 1)
+	var activeProfile config.Profile
+	if profileName != "" {
+		var ok bool
+		activeProfile, ok = cfg.Profile(profileName)
+		if !ok {
+			fmt.Printf("Error: no profile named %q in %s\n", profileName, cfgPath)
+			os.Exit(1)
+		}
+	}
 
-	// fmt.Printf("Pseudo command: %s\n", pseudo)
+	// CLI flag > env var > profile > built-in default.
+	if !flagsSet["p"] && os.Getenv("LLM_PROVIDER") == "" && activeProfile.Provider != "" {
+		provider = activeProfile.Provider
+	}
+	if !flagsSet["m"] && activeProfile.Model != "" {
+		model = activeProfile.Model
+	}
+
+	var temperature float32
+	switch {
+	case flagsSet["temperature"]:
+		temperature = float32(temperatureFlag)
+	case activeProfile.Temperature != 0:
+		temperature = activeProfile.Temperature
+	}
+
+	var systemPrompt string
+	switch {
+	case flagsSet["system-prompt"]:
+		systemPrompt = systemPromptFlag
+	case activeProfile.SystemPrompt != "":
+		systemPrompt = activeProfile.SystemPrompt
+	default:
+		systemPrompt, _ = cfg.ShellPrompt(parentProcessName)
+	}
+
+	// flag.Args() already excludes every recognized flag and its value, so
+	// the pseudo command never needs to special-case individual flags (and
+	// can't fall out of sync with them the way hand-stripping os.Args did).
+	pseudo := strings.Join(flag.Args(), " ")
 
 	if pseudo == "" {
 		thisCommand := filepath.Base(os.Args[0])
@@ -248,23 +329,88 @@ func main() {
 		os.Exit(1)
 	}
 
-	command, usage := makeCommand(pseudo, parentProcessName)
-	fmt.Printf("Looks insanely complicated? Don't panic. The answer is ...\n")
-	fmt.Printf("number of tokens used (total_tokens): %.1f\n", usage["total_tokens"])
-	color.Cyan(command)
+	llmProvider, err := NewProvider(provider)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
 
-	var confirmation string
-	fmt.Print("Run? (y/n) ")
-	fmt.Scanln(&confirmation)
+	translateReq := TranslateRequest{
+		Prompt:       pseudo,
+		Shell:        parentProcessName,
+		OS:           runtime.GOOS,
+		Model:        model,
+		Temperature:  temperature,
+		SystemPrompt: systemPrompt,
+	}
+	if session != nil {
+		translateReq.History = historyTurns(session)
+	}
+
+	result, err := translate(llmProvider, translateReq, stream)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	if session != nil {
+		session.Append("user", currentTurnContent(translateReq))
+		session.Append("assistant", result.Command)
+		if err := session.Save(); err != nil {
+			fmt.Println("Error saving session:", err)
+		}
+	}
 
 	var cmd *exec.Cmd
 
-	if confirmation == "y" {
+	for {
+		if result.Explanation != "" {
+			fmt.Println(result.Explanation)
+		}
+		if result.RequiresSudo {
+			fmt.Println("Note: this command requires elevated (sudo/admin) privileges.")
+		}
+
+		level, notes := assessSafety(llmProvider, result.Command, parentProcessName, runtime.GOOS)
+		level = escalate(dangerLevelToSafety(result.DangerLevel), level)
+
+		action, refinement := promptAction(result.Command, level, notes, session != nil)
+
+		if action == actionRefine {
+			translateReq = TranslateRequest{
+				Prompt:       refinement,
+				Shell:        parentProcessName,
+				OS:           runtime.GOOS,
+				Model:        model,
+				Temperature:  temperature,
+				SystemPrompt: systemPrompt,
+				History:      historyTurns(session),
+				Refine:       true,
+			}
+			result, err = translate(llmProvider, translateReq, false)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			session.Append("user", currentTurnContent(translateReq))
+			session.Append("assistant", result.Command)
+			if err := session.Save(); err != nil {
+				fmt.Println("Error saving session:", err)
+			}
+			continue
+		}
+
+		if action != actionRun {
+			return
+		}
+
+		command := result.Command
 		switch runtime.GOOS {
 		case "windows":
 			switch parentProcessName {
 			case "powershell.exe":
 				cmd = exec.Command("powershell.exe", "-c", command)
+			case "pwsh.exe":
+				cmd = exec.Command("pwsh.exe", "-c", command)
 			case "cmd.exe":
 				cmd = exec.Command("cmd.exe", "/C", command)
 			default:
@@ -277,6 +423,12 @@ func main() {
 				cmd = exec.Command("zsh", "-c", command)
 			case "bash":
 				cmd = exec.Command("bash", "-c", command)
+			case "fish":
+				cmd = exec.Command("fish", "-c", command)
+			case "nu":
+				cmd = exec.Command("nu", "-c", command)
+			case "pwsh":
+				cmd = exec.Command("pwsh", "-c", command)
 			default:
 				fmt.Printf("Error: unsupported shell %s on %s", parentProcessName, runtime.GOOS)
 				os.Exit(1)
@@ -289,6 +441,6 @@ func main() {
 		if err := cmd.Run(); err != nil {
 			fmt.Println("Error: ", err)
 		}
+		return
 	}
-
 }
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChatTurn is one prior turn of a persisted conversation (see the chat
+// package), threaded through to providers so a follow-up refinement keeps
+// the model's prior context instead of starting over.
+type ChatTurn struct {
+	Role    string
+	Content string
+}
+
+// TranslateRequest carries everything a Provider needs to translate a pseudo
+// command into a real shell command.
+type TranslateRequest struct {
+	Prompt string
+	Shell  string
+	OS     string
+	Model  string
+
+	// History holds prior turns of the conversation, oldest first. Empty
+	// for a one-off translation.
+	History []ChatTurn
+	// Refine indicates Prompt is a follow-up refinement of the previous
+	// command rather than a fresh pseudo command, so providers should send
+	// it as-is instead of wrapping it in the translation instructions.
+	Refine bool
+
+	// Temperature overrides the provider's sampling temperature when set
+	// from a config profile. Zero means "use the provider's own default".
+	Temperature float32
+	// SystemPrompt overrides the built-in translation instructions with a
+	// template from a config profile or a per-shell config entry. It may
+	// reference {{os}}, {{shell}} and {{prompt}} placeholders.
+	SystemPrompt string
+}
+
+// TranslateResponse is the result of a translation, including whatever token
+// usage information the backend reported.
+type TranslateResponse struct {
+	Command string
+	Usage   map[string]interface{}
+
+	// Explanation, DangerLevel and RequiresSudo are populated by providers
+	// that support structured output (see OpenAIProvider.Translate). They
+	// are empty/zero when a provider only returns plain text.
+	Explanation  string
+	DangerLevel  string // "low", "medium" or "high"
+	RequiresSudo bool
+}
+
+// Provider translates a pseudo command into a real command using a specific
+// LLM backend.
+type Provider interface {
+	Translate(req TranslateRequest) (TranslateResponse, error)
+}
+
+// SafetyClassification is the verdict of an advisory second LLM pass run
+// over a command before it's executed.
+type SafetyClassification struct {
+	Level       string // "safe", "warn" or "dangerous"
+	Explanation string
+}
+
+// SafetyClassifier is implemented by providers that can run a cheap,
+// second-opinion pass classifying a command's risk before execution.
+type SafetyClassifier interface {
+	ClassifySafety(command, shell, osName string) (SafetyClassification, error)
+}
+
+// StreamingProvider is implemented by providers that can stream the
+// translated command token by token instead of waiting for the full
+// response. tokens yields partial content as it arrives and is closed once
+// the stream ends; result then yields exactly one TranslateResponse holding
+// the fully assembled command (and usage, if the backend reported any).
+type StreamingProvider interface {
+	Provider
+	TranslateStream(req TranslateRequest) (tokens <-chan string, result <-chan TranslateResponse, err error)
+}
+
+// Supported provider names for the -p flag / LLM_PROVIDER env var.
+const (
+	providerOpenAI    = "openai"
+	providerAnthropic = "anthropic"
+	providerMistral   = "mistral"
+	providerLocalAI   = "localai"
+)
+
+// NewProvider returns the Provider registered under name, or an error if name
+// is not one of the supported providers.
+func NewProvider(name string) (Provider, error) {
+	switch name {
+	case providerOpenAI:
+		return &OpenAIProvider{}, nil
+	case providerAnthropic:
+		return &AnthropicProvider{}, nil
+	case providerMistral:
+		return &MistralProvider{}, nil
+	case providerLocalAI:
+		return &LocalAIProvider{}, nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q (supported: %s, %s, %s, %s)",
+			name, providerOpenAI, providerAnthropic, providerMistral, providerLocalAI)
+	}
+}
+
+// translationPrompt builds the user-facing instruction sent to the backend,
+// shared by every provider so the translation quality stays consistent
+// regardless of which backend answers it. req.SystemPrompt, when set from a
+// config profile or a per-shell config entry, replaces the built-in
+// instructions; it may reference {{os}}, {{shell}} and {{prompt}}.
+func translationPrompt(req TranslateRequest) string {
+	if req.SystemPrompt != "" {
+		replacer := strings.NewReplacer("{{os}}", req.OS, "{{shell}}", req.Shell, "{{prompt}}", req.Prompt)
+		return replacer.Replace(req.SystemPrompt)
+	}
+
+	return "Convert this pseudo command into a real command that can be run on " +
+		req.OS + " and " + req.Shell + " command shell. Note that the command might include misspelled, invalid or " +
+		"imagined arguments or even imagined program names. Try your best to convert it " +
+		"into an actual command that would do what the command seems to be intended to do.\n\n" +
+		req.Prompt + "\n\nRespond only with the command."
+}
+
+// resolveModel returns requested if the user explicitly overrode the -m
+// flag's default, otherwise the provider's own default model.
+func resolveModel(requested, providerDefault string) string {
+	if requested == "" || requested == defaultModelFlag {
+		return providerDefault
+	}
+	return requested
+}
+
+// currentTurnContent returns the literal content a provider should send for
+// the current turn: the full translation instructions for a fresh pseudo
+// command, or the raw refinement text when continuing a conversation.
+func currentTurnContent(req TranslateRequest) string {
+	if req.Refine {
+		return req.Prompt
+	}
+	return translationPrompt(req)
+}
+
+// buildTurns returns req's prior history plus the current turn, ready for a
+// provider to convert into its own message type.
+func buildTurns(req TranslateRequest) []ChatTurn {
+	turns := append([]ChatTurn{}, req.History...)
+	return append(turns, ChatTurn{Role: "user", Content: currentTurnContent(req)})
+}
+
+// redactSecret returns a short, non-sensitive stand-in for a secret value
+// suitable for verbose/debug logging: "(unset)" if empty, "***" if it is 4
+// bytes or shorter, otherwise a fixed-length prefix followed by an ellipsis
+// so the log can confirm which credential loaded without ever printing it
+// in full.
+func redactSecret(secret string) string {
+	if secret == "" {
+		return "(unset)"
+	}
+	if len(secret) <= 4 {
+		return "***"
+	}
+	return secret[:4] + "..."
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// anthropicDefaultModel is used when the user didn't pass -m explicitly.
+const anthropicDefaultModel = "claude-3-haiku-20240307"
+
+const anthropicAPIKeyInfo = "Goto https://console.anthropic.com/settings/keys to get your API key. Set the API key on CLI by 'export ANTHROPIC_API_KEY=key' on Linux and MacOS or $Env:ANTHROPIC_API_KEY = 'key' on Windows PowerShell\n\n"
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	MaxTokens   int                `json:"max_tokens"`
+	Messages    []anthropicMessage `json:"messages"`
+	Temperature float32            `json:"temperature,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// toAnthropicMessages converts generic chat turns into the Anthropic
+// messages API shape.
+func toAnthropicMessages(turns []ChatTurn) []anthropicMessage {
+	messages := make([]anthropicMessage, len(turns))
+	for i, t := range turns {
+		messages[i] = anthropicMessage{Role: t.Role, Content: t.Content}
+	}
+	return messages
+}
+
+// AnthropicProvider talks to the Anthropic Claude messages API.
+type AnthropicProvider struct{}
+
+func (p *AnthropicProvider) Translate(req TranslateRequest) (TranslateResponse, error) {
+	apiKey := os.Getenv("ANTHROPIC_API_KEY")
+	if apiKey == "" {
+		fmt.Printf("Error: ANTHROPIC_API_KEY is not set. %s", anthropicAPIKeyInfo)
+		os.Exit(1)
+	}
+	debugPrintf("ANTHROPIC_API_KEY: %s", redactSecret(apiKey))
+
+	url := "https://api.anthropic.com/v1/messages"
+	debugPrintf("API URL: %s", url)
+
+	payload := anthropicRequest{
+		Model:       resolveModel(req.Model, anthropicDefaultModel),
+		MaxTokens:   1000,
+		Messages:    toAnthropicMessages(buildTurns(req)),
+		Temperature: req.Temperature,
+	}
+
+	data, err := json.Marshal(payload)
+	debugPrintf("\n%s\n", data)
+	if err != nil {
+		fmt.Println("Error marshaling payload:", err)
+		os.Exit(1)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		fmt.Println("Error creating request:", err)
+		os.Exit(1)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", apiKey)
+	httpReq.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Println("Error reading response:", err)
+		os.Exit(1)
+	}
+
+	var result anthropicResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		fmt.Println("Error unmarshaling response:", err)
+		os.Exit(1)
+	}
+	if result.Error != nil {
+		return TranslateResponse{}, fmt.Errorf("error type %s: %s", result.Error.Type, result.Error.Message)
+	}
+	debugPrintf("result:\n%#v\n", result)
+	if len(result.Content) == 0 {
+		return TranslateResponse{}, fmt.Errorf("empty response content")
+	}
+
+	usage := map[string]interface{}{
+		"prompt_tokens":     float64(result.Usage.InputTokens),
+		"completion_tokens": float64(result.Usage.OutputTokens),
+		"total_tokens":      float64(result.Usage.InputTokens + result.Usage.OutputTokens),
+	}
+	debugPrintf("usage:\n%#v\n", usage)
+
+	return TranslateResponse{Command: result.Content[0].Text, Usage: usage}, nil
+}
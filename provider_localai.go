@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// localAIDefaultModel is used when the user didn't pass -m explicitly. Local
+// OpenAI-compatible servers (LocalAI, Ollama, ...) usually route by model
+// name to whichever model file is configured, so this is just a placeholder.
+const localAIDefaultModel = "gpt-3.5-turbo"
+
+const localAIBaseURLInfo = "Set the base URL of your local OpenAI-compatible server (e.g. LocalAI or Ollama) by 'export LOCALAI_BASE_URL=http://localhost:8080' on Linux and MacOS or $Env:LOCALAI_BASE_URL = 'http://localhost:8080' on Windows PowerShell\n\n"
+
+// LocalAIProvider talks to a self-hosted OpenAI-compatible endpoint such as
+// LocalAI or Ollama, allowing translation to run against a local model for
+// privacy or cost reasons.
+type LocalAIProvider struct{}
+
+func (p *LocalAIProvider) Translate(req TranslateRequest) (TranslateResponse, error) {
+	baseURL := os.Getenv("LOCALAI_BASE_URL")
+	if baseURL == "" {
+		fmt.Printf("Error: LOCALAI_BASE_URL is not set. %s", localAIBaseURLInfo)
+		os.Exit(1)
+	}
+	debugPrintf("LOCALAI_BASE_URL: %s", redactSecret(baseURL))
+
+	url := strings.TrimRight(baseURL, "/") + "/v1/chat/completions"
+	debugPrintf("API URL: %s", url)
+
+	payload := ChatCompletionRequest{
+		Model:       resolveModel(req.Model, localAIDefaultModel),
+		Messages:    toChatCompletionMessages(buildTurns(req)),
+		MaxTokens:   1000,
+		Temperature: req.Temperature,
+	}
+
+	data, err := json.Marshal(payload)
+	debugPrintf("\n%s\n", data)
+	if err != nil {
+		fmt.Println("Error marshaling payload:", err)
+		os.Exit(1)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		fmt.Println("Error creating request:", err)
+		os.Exit(1)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	// Local servers typically don't require authentication, but forward the
+	// variable as a bearer token for setups that do enforce one.
+	if apiKey := os.Getenv("LOCALAI_API_KEY"); apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Println("Error reading response:", err)
+		os.Exit(1)
+	}
+
+	var result map[string]interface{}
+	json.Unmarshal(body, &result)
+	if _, ok := result["error"]; ok {
+		errorDetails := result["error"].(map[string]interface{})
+		return TranslateResponse{}, fmt.Errorf("error code %v: %s", errorDetails["code"], errorDetails["message"])
+	}
+	debugPrintf("result:\n%#v\n", result)
+	choices, ok := result["choices"].([]interface{})
+	if !ok || len(choices) == 0 {
+		return TranslateResponse{}, fmt.Errorf("no choices in response")
+	}
+	firstChoice, ok := choices[0].(map[string]interface{})
+	if !ok {
+		return TranslateResponse{}, fmt.Errorf("malformed choice in response")
+	}
+	message, ok := firstChoice["message"].(map[string]interface{})
+	if !ok {
+		return TranslateResponse{}, fmt.Errorf("malformed message in response")
+	}
+	content, _ := message["content"].(string)
+	usage, _ := result["usage"].(map[string]interface{})
+	debugPrintf("usage:\n%#v\n", usage)
+
+	return TranslateResponse{Command: content, Usage: usage}, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// mistralDefaultModel is used when the user didn't pass -m explicitly.
+const mistralDefaultModel = "mistral-small-latest"
+
+const mistralAPIKeyInfo = "Goto https://console.mistral.ai/api-keys/ to get your API key. Set the API key on CLI by 'export MISTRAL_API_KEY=key' on Linux and MacOS or $Env:MISTRAL_API_KEY = 'key' on Windows PowerShell\n\n"
+
+// MistralProvider talks to the Mistral AI chat completions API, which is
+// wire-compatible with the OpenAI chat completions format.
+type MistralProvider struct{}
+
+func (p *MistralProvider) Translate(req TranslateRequest) (TranslateResponse, error) {
+	apiKey := os.Getenv("MISTRAL_API_KEY")
+	if apiKey == "" {
+		fmt.Printf("Error: MISTRAL_API_KEY is not set. %s", mistralAPIKeyInfo)
+		os.Exit(1)
+	}
+	debugPrintf("MISTRAL_API_KEY: %s", redactSecret(apiKey))
+
+	url := "https://api.mistral.ai/v1/chat/completions"
+	debugPrintf("API URL: %s", url)
+
+	payload := ChatCompletionRequest{
+		Model:       resolveModel(req.Model, mistralDefaultModel),
+		Messages:    toChatCompletionMessages(buildTurns(req)),
+		MaxTokens:   1000,
+		Temperature: req.Temperature,
+	}
+
+	data, err := json.Marshal(payload)
+	debugPrintf("\n%s\n", data)
+	if err != nil {
+		fmt.Println("Error marshaling payload:", err)
+		os.Exit(1)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		fmt.Println("Error creating request:", err)
+		os.Exit(1)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Println("Error reading response:", err)
+		os.Exit(1)
+	}
+
+	var result map[string]interface{}
+	json.Unmarshal(body, &result)
+	if _, ok := result["error"]; ok {
+		errorDetails := result["error"].(map[string]interface{})
+		return TranslateResponse{}, fmt.Errorf("error code %v: %s", errorDetails["code"], errorDetails["message"])
+	}
+	debugPrintf("result:\n%#v\n", result)
+	choices, ok := result["choices"].([]interface{})
+	if !ok || len(choices) == 0 {
+		return TranslateResponse{}, fmt.Errorf("no choices in response")
+	}
+	firstChoice, ok := choices[0].(map[string]interface{})
+	if !ok {
+		return TranslateResponse{}, fmt.Errorf("malformed choice in response")
+	}
+	message, ok := firstChoice["message"].(map[string]interface{})
+	if !ok {
+		return TranslateResponse{}, fmt.Errorf("malformed message in response")
+	}
+	content, _ := message["content"].(string)
+	usage, _ := result["usage"].(map[string]interface{})
+	debugPrintf("usage:\n%#v\n", usage)
+
+	return TranslateResponse{Command: content, Usage: usage}, nil
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// openAIDefaultModel is used when the user didn't pass -m explicitly.
+const openAIDefaultModel = "gpt-3.5-turbo"
+
+const openAIAPIKeyInfo = "Goto https://platform.openai.com/account/api-keys to get your API key. Set the API key on CLI by 'export OPENAI_API_KEY=key' on Linux and MacOS or $Env:OPENAI_API_KEY = 'key' on Windows PowerShell\n\n"
+
+type ChatCompletionRequest struct {
+	Model            string                  `json:"model"`
+	Messages         []ChatCompletionMessage `json:"messages"`
+	MaxTokens        int                     `json:"max_tokens,omitempty"`
+	Temperature      float32                 `json:"temperature,omitempty"`
+	TopP             float32                 `json:"top_p,omitempty"`
+	N                int                     `json:"n,omitempty"`
+	Stream           bool                    `json:"stream,omitempty"`
+	Stop             []string                `json:"stop,omitempty"`
+	PresencePenalty  float32                 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty float32                 `json:"frequency_penalty,omitempty"`
+	// LogitBias is must be a token id string (specified by their token ID in the tokenizer), not a word string.
+	// incorrect: `"logit_bias":{"You": 6}`, correct: `"logit_bias":{"1639": 6}`
+	// refs: https://platform.openai.com/docs/api-reference/chat/create#chat/create-logit_bias
+	LogitBias     map[string]int       `json:"logit_bias,omitempty"`
+	User          string               `json:"user,omitempty"`
+	Functions     []FunctionDefinition `json:"functions,omitempty"`
+	FunctionCall  any                  `json:"function_call,omitempty"`
+	StreamOptions *StreamOptions       `json:"stream_options,omitempty"`
+}
+
+// StreamOptions controls streamed chat-completion behavior. IncludeUsage
+// asks the API to emit one final chunk carrying the token usage for the
+// whole response, since usage is otherwise only available on non-streamed
+// completions.
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type ChatCompletionMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+
+	// This property isn't in the official documentation, but it's in
+	// the documentation for the official library for python:
+	// - https://github.com/openai/openai-python/blob/main/chatml.md
+	// - https://github.com/openai/openai-cookbook/blob/main/examples/How_to_count_tokens_with_tiktoken.ipynb
+	Name string `json:"name,omitempty"`
+
+	FunctionCall *FunctionCall `json:"function_call,omitempty"`
+}
+
+type FunctionCall struct {
+	Name string `json:"name,omitempty"`
+	// call function with arguments in JSON format
+	Arguments string `json:"arguments,omitempty"`
+}
+
+type FunctionDefinition struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Parameters is an object describing the function.
+	// You can pass json.RawMessage to describe the schema,
+	// or you can pass in a struct which serializes to the proper JSON schema.
+	// The jsonschema package is provided for convenience, but you should
+	// consider another specialized library if you require more complex schemas.
+	Parameters any `json:"parameters"`
+}
+
+// runShellCommandFunction forces the model to return the translated command
+// together with safety metadata instead of free-form text.
+var runShellCommandFunction = FunctionDefinition{
+	Name:        "run_shell_command",
+	Description: "Report the real shell command to run along with safety metadata about it.",
+	Parameters: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"command": map[string]interface{}{
+				"type":        "string",
+				"description": "The real, executable shell command.",
+			},
+			"explanation": map[string]interface{}{
+				"type":        "string",
+				"description": "A short, plain-language explanation of what the command does.",
+			},
+			"danger_level": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"low", "medium", "high"},
+				"description": "How risky the command is to run: low for read-only/harmless commands, medium for commands that modify state, high for destructive or irreversible commands.",
+			},
+			"requires_sudo": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Whether the command needs elevated/admin privileges to run.",
+			},
+		},
+		"required": []string{"command", "explanation", "danger_level", "requires_sudo"},
+	},
+}
+
+// runShellCommandArgs mirrors the JSON arguments the model returns for the
+// run_shell_command function call.
+type runShellCommandArgs struct {
+	Command      string `json:"command"`
+	Explanation  string `json:"explanation"`
+	DangerLevel  string `json:"danger_level"`
+	RequiresSudo bool   `json:"requires_sudo"`
+}
+
+// toChatCompletionMessages converts generic chat turns into the OpenAI
+// message shape shared by OpenAI, Mistral and LocalAI.
+func toChatCompletionMessages(turns []ChatTurn) []ChatCompletionMessage {
+	messages := make([]ChatCompletionMessage, len(turns))
+	for i, t := range turns {
+		messages[i] = ChatCompletionMessage{Role: t.Role, Content: t.Content}
+	}
+	return messages
+}
+
+// OpenAIProvider talks to the OpenAI chat completions API.
+type OpenAIProvider struct{}
+
+func (p *OpenAIProvider) Translate(req TranslateRequest) (TranslateResponse, error) {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		fmt.Printf("Error: OPENAI_API_KEY is not set. %s", openAIAPIKeyInfo)
+		os.Exit(1)
+	}
+	debugPrintf("OPENAI_API_KEY: %s", redactSecret(apiKey))
+
+	url := "https://api.openai.com/v1/chat/completions"
+	debugPrintf("API URL: %s", url)
+
+	payload := ChatCompletionRequest{
+		Model:        resolveModel(req.Model, openAIDefaultModel),
+		Messages:     toChatCompletionMessages(buildTurns(req)),
+		MaxTokens:    1000,
+		Temperature:  req.Temperature,
+		Functions:    []FunctionDefinition{runShellCommandFunction},
+		FunctionCall: map[string]string{"name": runShellCommandFunction.Name},
+	}
+
+	data, err := json.Marshal(payload)
+	debugPrintf("\n%s\n", data)
+	if err != nil {
+		fmt.Println("Error marshaling payload:", err)
+		os.Exit(1)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		fmt.Println("Error creating request:", err)
+		os.Exit(1)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Println("Error reading response:", err)
+		os.Exit(1)
+	}
+
+	var result map[string]interface{}
+	json.Unmarshal(body, &result)
+	// check if error is returned in result map
+	if _, ok := result["error"]; ok {
+		errorDetails := result["error"].(map[string]interface{})
+		return TranslateResponse{}, fmt.Errorf("error code %v: %s", errorDetails["code"], errorDetails["message"])
+	}
+	debugPrintf("result:\n%#v\n", result)
+	choices, ok := result["choices"].([]interface{})
+	if !ok || len(choices) == 0 {
+		return TranslateResponse{}, fmt.Errorf("no choices in response")
+	}
+	firstChoice, ok := choices[0].(map[string]interface{})
+	if !ok {
+		return TranslateResponse{}, fmt.Errorf("malformed choice in response")
+	}
+	message, ok := firstChoice["message"].(map[string]interface{})
+	if !ok {
+		return TranslateResponse{}, fmt.Errorf("malformed message in response")
+	}
+	usage, _ := result["usage"].(map[string]interface{})
+	debugPrintf("usage:\n%#v\n", usage)
+
+	functionCall, ok := message["function_call"].(map[string]interface{})
+	if !ok {
+		// model didn't comply with the forced function call; fall back to
+		// whatever plain text it returned.
+		content, _ := message["content"].(string)
+		return TranslateResponse{Command: content, Usage: usage}, nil
+	}
+
+	arguments, _ := functionCall["arguments"].(string)
+	var args runShellCommandArgs
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return TranslateResponse{}, fmt.Errorf("decoding run_shell_command arguments: %w", err)
+	}
+
+	return TranslateResponse{
+		Command:      args.Command,
+		Usage:        usage,
+		Explanation:  args.Explanation,
+		DangerLevel:  args.DangerLevel,
+		RequiresSudo: args.RequiresSudo,
+	}, nil
+}
+
+// classifySafetyFunction forces the model to return a risk verdict for an
+// already-translated command, used as a cheap second opinion before it's
+// run.
+var classifySafetyFunction = FunctionDefinition{
+	Name:        "classify_command_safety",
+	Description: "Classify how risky it is to run the given shell command.",
+	Parameters: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"level": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"safe", "warn", "dangerous"},
+				"description": "safe for read-only/harmless commands, warn for commands that modify state but are recoverable, dangerous for destructive or irreversible commands.",
+			},
+			"explanation": map[string]interface{}{
+				"type":        "string",
+				"description": "A short explanation of the risk, or of why the command is safe.",
+			},
+		},
+		"required": []string{"level", "explanation"},
+	},
+}
+
+type classifySafetyArgs struct {
+	Level       string `json:"level"`
+	Explanation string `json:"explanation"`
+}
+
+// ClassifySafety asks the model for a second opinion on command's risk. It
+// always uses openAIDefaultModel rather than the user's chosen -m model,
+// since this is meant to be a cheap advisory check, not the main
+// translation.
+func (p *OpenAIProvider) ClassifySafety(command, shell, osName string) (SafetyClassification, error) {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		return SafetyClassification{}, fmt.Errorf("OPENAI_API_KEY is not set")
+	}
+
+	url := "https://api.openai.com/v1/chat/completions"
+
+	payload := ChatCompletionRequest{
+		Model: openAIDefaultModel,
+		Messages: []ChatCompletionMessage{
+			{Role: "user", Content: "Classify the risk of running this command on " + osName + " in the " + shell + " shell:\n\n" + command},
+		},
+		MaxTokens:    200,
+		Functions:    []FunctionDefinition{classifySafetyFunction},
+		FunctionCall: map[string]string{"name": classifySafetyFunction.Name},
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return SafetyClassification{}, fmt.Errorf("marshaling payload: %w", err)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return SafetyClassification{}, fmt.Errorf("creating request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return SafetyClassification{}, fmt.Errorf("calling OpenAI API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return SafetyClassification{}, fmt.Errorf("reading response: %w", err)
+	}
+
+	var result map[string]interface{}
+	json.Unmarshal(body, &result)
+	if _, ok := result["error"]; ok {
+		errorDetails := result["error"].(map[string]interface{})
+		return SafetyClassification{}, fmt.Errorf("error code %v: %s", errorDetails["code"], errorDetails["message"])
+	}
+	choices, ok := result["choices"].([]interface{})
+	if !ok || len(choices) == 0 {
+		return SafetyClassification{}, fmt.Errorf("no choices in response")
+	}
+	message, _ := choices[0].(map[string]interface{})["message"].(map[string]interface{})
+	functionCall, ok := message["function_call"].(map[string]interface{})
+	if !ok {
+		return SafetyClassification{}, fmt.Errorf("model did not return a safety classification")
+	}
+
+	arguments, _ := functionCall["arguments"].(string)
+	var args classifySafetyArgs
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return SafetyClassification{}, fmt.Errorf("decoding classify_command_safety arguments: %w", err)
+	}
+
+	return SafetyClassification{Level: args.Level, Explanation: args.Explanation}, nil
+}
+
+// TranslateStream is like Translate, but requests a streamed response and
+// delivers tokens as they arrive over the returned channel instead of
+// blocking until the full completion is available.
+func (p *OpenAIProvider) TranslateStream(req TranslateRequest) (<-chan string, <-chan TranslateResponse, error) {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		fmt.Printf("Error: OPENAI_API_KEY is not set. %s", openAIAPIKeyInfo)
+		os.Exit(1)
+	}
+	debugPrintf("OPENAI_API_KEY: %s", redactSecret(apiKey))
+
+	url := "https://api.openai.com/v1/chat/completions"
+	debugPrintf("API URL: %s", url)
+
+	payload := ChatCompletionRequest{
+		Model:         resolveModel(req.Model, openAIDefaultModel),
+		Messages:      toChatCompletionMessages(buildTurns(req)),
+		MaxTokens:     1000,
+		Temperature:   req.Temperature,
+		Stream:        true,
+		StreamOptions: &StreamOptions{IncludeUsage: true},
+	}
+
+	data, err := json.Marshal(payload)
+	debugPrintf("\n%s\n", data)
+	if err != nil {
+		fmt.Println("Error marshaling payload:", err)
+		os.Exit(1)
+	}
+
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		fmt.Println("Error creating request:", err)
+		os.Exit(1)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := (&http.Client{}).Do(httpReq)
+	if err != nil {
+		return nil, nil, fmt.Errorf("calling OpenAI API: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("OpenAI API returned %s: %s", resp.Status, body)
+	}
+
+	tokens := make(chan string)
+	result := make(chan TranslateResponse, 1)
+
+	go func() {
+		defer resp.Body.Close()
+		defer close(tokens)
+		defer close(result)
+
+		var command strings.Builder
+		var usage map[string]interface{}
+		reader := bufio.NewReader(resp.Body)
+		var event strings.Builder
+
+		for {
+			line, readErr := reader.ReadString('\n')
+			event.WriteString(line)
+
+			if strings.HasSuffix(event.String(), "\n\n") {
+				for _, part := range strings.Split(strings.TrimSpace(event.String()), "\n") {
+					part = strings.TrimPrefix(part, "data: ")
+					if part == "" || part == "[DONE]" {
+						continue
+					}
+					var chunk map[string]interface{}
+					if jsonErr := json.Unmarshal([]byte(part), &chunk); jsonErr != nil {
+						debugPrintf("error decoding stream chunk: %v\n", jsonErr)
+						continue
+					}
+					// The final chunk of a stream requested with
+					// stream_options.include_usage carries the usage totals
+					// and an empty choices array, so check for it before
+					// skipping chunks with no choices.
+					if u, ok := chunk["usage"].(map[string]interface{}); ok {
+						usage = u
+					}
+					choices, _ := chunk["choices"].([]interface{})
+					if len(choices) == 0 {
+						continue
+					}
+					delta, _ := choices[0].(map[string]interface{})["delta"].(map[string]interface{})
+					token, _ := delta["content"].(string)
+					if token != "" {
+						command.WriteString(token)
+						tokens <- token
+					}
+				}
+				event.Reset()
+			}
+
+			if strings.Contains(line, "[DONE]") || readErr != nil {
+				result <- TranslateResponse{Command: command.String(), Usage: usage}
+				return
+			}
+		}
+	}()
+
+	return tokens, result, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// denylistPattern is one static, regex-matched pattern for commands that are
+// almost never intentional and never worth running unconfirmed.
+type denylistPattern struct {
+	re          *regexp.Regexp
+	description string
+}
+
+var commandDenylist = []denylistPattern{
+	{regexp.MustCompile(`(?i)\brm\s+(?:-\S*\s+)*(?:` +
+		`-[a-z]*[rf][a-z]*[rf][a-z]*\s+(?:-\S*\s+)*` +
+		`|(?:-[a-z]*r[a-z]*|--recursive)\s+(?:-\S*\s+)*(?:-[a-z]*f[a-z]*|--force)\s+(?:-\S*\s+)*` +
+		`|(?:-[a-z]*f[a-z]*|--force)\s+(?:-\S*\s+)*(?:-[a-z]*r[a-z]*|--recursive)\s+(?:-\S*\s+)*` +
+		`)/(?:\*|\s|$)`), "recursively force-removes the root filesystem"},
+	{regexp.MustCompile(`\bmkfs\b`), "reformats a filesystem, destroying its contents"},
+	{regexp.MustCompile(`\bdd\s+if=`), "performs a low-level raw disk copy"},
+	{regexp.MustCompile(`:\(\)\s*\{\s*:\s*\|\s*:\s*&\s*\}\s*;\s*:`), "is a fork bomb"},
+	{regexp.MustCompile(`curl[^|]*\|\s*(sudo\s+)?(ba|z)?sh\b`), "pipes a downloaded script directly into a shell"},
+	{regexp.MustCompile(`chmod\s+-R\s+777\s+/(\s|$)`), "recursively makes the entire filesystem world-writable"},
+}
+
+// matchDenylist returns the description of the first denylist pattern that
+// matches command, or "" if none match.
+func matchDenylist(command string) string {
+	for _, p := range commandDenylist {
+		if p.re.MatchString(command) {
+			return p.description
+		}
+	}
+	return ""
+}
+
+// powershellQuote wraps s in a single-quoted PowerShell string literal,
+// doubling any embedded single quotes so the result is always parsed back
+// as one opaque string argument.
+func powershellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// dryRunCheck runs command through a shell-specific syntax/dry-run check
+// where one is available for shell. ok is true when the check passed, or
+// when no dry-run mode applies to shell.
+func dryRunCheck(command, shell string) (ok bool, output string) {
+	var cmd *exec.Cmd
+	switch shell {
+	case "bash":
+		cmd = exec.Command("bash", "-n", "-c", command)
+	case "zsh":
+		cmd = exec.Command("zsh", "-n", "-c", command)
+	case "fish":
+		cmd = exec.Command("fish", "-n", "-c", command)
+	case "powershell.exe", "pwsh.exe", "pwsh":
+		// Parse only, via the PowerShell language parser: this compiles the
+		// command without running it, so (unlike -WhatIf, which only
+		// cmdlets that implement SupportsShouldProcess honor) it can never
+		// execute the candidate command ahead of user confirmation.
+		// Parse errors are printed and turned into a non-zero exit code.
+		parseScript := "$parseErrors = $null; $null = [System.Management.Automation.Language.Parser]::ParseInput(" +
+			powershellQuote(command) + ", [ref]$null, [ref]$parseErrors); " +
+			"if ($parseErrors.Count -gt 0) { $parseErrors | ForEach-Object { $_.Message }; exit 1 }"
+		cmd = exec.Command(shell, "-NoProfile", "-NonInteractive", "-Command", parseScript)
+	}
+	if cmd == nil {
+		return true, ""
+	}
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return false, string(out)
+	}
+	return true, string(out)
+}
+
+// safetyRank orders the three safety levels from least to most severe.
+var safetyRank = map[string]int{"": -1, "safe": 0, "warn": 1, "dangerous": 2}
+
+// escalate returns the more severe of two safety levels.
+func escalate(a, b string) string {
+	if safetyRank[b] > safetyRank[a] {
+		return b
+	}
+	return a
+}
+
+// dangerLevelToSafety maps the function-calling danger_level vocabulary
+// (low/medium/high, see runShellCommandFunction) onto the safety pass's
+// vocabulary (safe/warn/dangerous) so both gates can be combined into one
+// escalating verdict.
+func dangerLevelToSafety(dangerLevel string) string {
+	switch dangerLevel {
+	case "low":
+		return "safe"
+	case "medium":
+		return "warn"
+	case "high":
+		return "dangerous"
+	default:
+		return ""
+	}
+}
+
+// assessSafety combines the static denylist, a shell dry-run check and an
+// optional second classification LLM call into one escalating safety level,
+// along with human-readable notes to show the user before they confirm.
+func assessSafety(llmProvider Provider, command, shell, osName string) (level string, notes []string) {
+	if reason := matchDenylist(command); reason != "" {
+		return "dangerous", []string{"Denylist match: this command " + reason + "."}
+	}
+
+	if ok, output := dryRunCheck(command, shell); !ok {
+		notes = append(notes, "Dry-run check failed:\n"+strings.TrimSpace(output))
+		level = "warn"
+	}
+
+	if classifier, ok := llmProvider.(SafetyClassifier); ok {
+		classification, err := classifier.ClassifySafety(command, shell, osName)
+		if err != nil {
+			debugPrintf("safety classification failed: %v\n", err)
+		} else {
+			if classification.Explanation != "" {
+				notes = append(notes, classification.Explanation)
+			}
+			level = escalate(level, classification.Level)
+		}
+	}
+
+	if level == "" {
+		level = "safe"
+	}
+	return level, notes
+}